@@ -0,0 +1,73 @@
+package decorator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attribute escape codes beyond the basic 16-colour palette. These compose
+// with the Fg*/Bg* constants and with each other via Style.
+const (
+	Underline     LineColourEnum = "\033[4m"
+	Italic        LineColourEnum = "\033[3m"
+	Reverse       LineColourEnum = "\033[7m"
+	Dim           LineColourEnum = "\033[2m"
+	Strikethrough LineColourEnum = "\033[9m"
+)
+
+// Fg256 returns a foreground LineColourEnum for the given index into the
+// terminal's 256-colour palette.
+func Fg256(n uint8) LineColourEnum {
+	return LineColourEnum(fmt.Sprintf("\033[38;5;%dm", n))
+}
+
+// Bg256 returns a background LineColourEnum for the given index into the
+// terminal's 256-colour palette.
+func Bg256(n uint8) LineColourEnum {
+	return LineColourEnum(fmt.Sprintf("\033[48;5;%dm", n))
+}
+
+// FgRGB returns a truecolor foreground LineColourEnum for the given RGB
+// triple, for terminals that support 24-bit colour.
+func FgRGB(r, g, b uint8) LineColourEnum {
+	return LineColourEnum(fmt.Sprintf("\033[38;2;%d;%d;%dm", r, g, b))
+}
+
+// BgRGB returns a truecolor background LineColourEnum for the given RGB
+// triple, for terminals that support 24-bit colour.
+func BgRGB(r, g, b uint8) LineColourEnum {
+	return LineColourEnum(fmt.Sprintf("\033[48;2;%d;%d;%dm", r, g, b))
+}
+
+// A Style composes one or more attributes - colours, weights, decorations -
+// so a single LineColour span can be e.g. bold, red, and underlined at once.
+type Style []LineColourEnum
+
+// Compose concatenates every attribute in the style into the single
+// LineColourEnum that LineColour.Colour expects.
+func (s Style) Compose() LineColourEnum {
+	var b strings.Builder
+	for _, attr := range s {
+		b.WriteString(string(attr))
+	}
+	return LineColourEnum(b.String())
+}
+
+// A semantic style name, e.g. "error", "warning", "hint", looked up through
+// a Decorator's palette rather than hard-coding a concrete Style everywhere
+// that meaning is used.
+type PaletteName string
+
+// SetPalette installs a mapping from semantic style names to concrete
+// styles, so callers can refer to "error"/"warning"/"hint" and remap what
+// those mean in one place.
+func (dec *Decorator) SetPalette(palette map[PaletteName]Style) {
+	dec.palette = palette
+}
+
+// Palette looks up the concrete Style registered for name via SetPalette.
+// ok is false if no such name has been registered.
+func (dec *Decorator) Palette(name PaletteName) (style Style, ok bool) {
+	style, ok = dec.palette[name]
+	return style, ok
+}
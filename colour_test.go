@@ -0,0 +1,35 @@
+package decorator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteColouredNestedSpans(t *testing.T) {
+	clusters := segmentClusters("0123456789", 4)
+	colours := []LineColour{
+		{From: 0, To: 10, Colour: FgRed},
+		{From: 2, To: 5, Colour: Underline},
+	}
+
+	var b strings.Builder
+	writeColoured(&b, clusters, colours)
+	got := b.String()
+
+	want := string(FgRed) + "01" +
+		string(Normal) + string(FgRed) + string(Underline) + "234" +
+		string(Normal) + string(FgRed) + "56789" +
+		string(Normal)
+
+	if got != want {
+		t.Fatalf("writeColoured nesting mismatch:\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestStyleCompose(t *testing.T) {
+	style := Style{FgRed, Underline}
+	want := LineColourEnum(string(FgRed) + string(Underline))
+	if got := style.Compose(); got != want {
+		t.Fatalf("Compose() = %q, want %q", got, want)
+	}
+}
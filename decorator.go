@@ -43,6 +43,7 @@ type commentInfo struct {
 
 type lineInfo struct {
 	text           string
+	clusters       []cluster
 	colours        []LineColour
 	meta           LineMetadata
 	topComments    []commentInfo
@@ -51,10 +52,10 @@ type lineInfo struct {
 
 // Describes how to colour a particular segment of a line.
 type LineColour struct {
-	// The index of the first character to colour.
+	// The index of the first grapheme cluster to colour.
 	From int
 
-	// The index of the last character (non-inclusive) to colour.
+	// The index of the last grapheme cluster (non-inclusive) to colour.
 	To int
 
 	// The colouring to apply.
@@ -71,23 +72,50 @@ type LineMetadata struct {
 	cachedPrefix string
 }
 
+// The number of columns a tab expands to when Decorator.SetTabWidth hasn't
+// been called.
+const defaultTabWidth = 4
+
 // The main type responsible for this library's functionality.
 type Decorator struct {
-	lines []lineInfo
+	lines          []lineInfo
+	tabWidth       int
+	palette        map[PaletteName]Style
+	multilineSpans []Span
+	layout         LayoutOptions
+}
+
+// Sets how many columns a '\t' in an added line expands to. A width less
+// than 1 is ignored. If this is never called, defaultTabWidth is used.
+func (dec *Decorator) SetTabWidth(width int) {
+	if width < 1 {
+		return
+	}
+	dec.tabWidth = width
 }
 
-// Adds a new line to be decorated.
+func (dec *Decorator) tabWidthOrDefault() int {
+	if dec.tabWidth <= 0 {
+		return defaultTabWidth
+	}
+	return dec.tabWidth
+}
+
+// Adds a new line to be decorated. Lines may contain tabs, which are
+// expanded to Decorator.SetTabWidth columns when rendered, but may not
+// contain '\n' or '\r'.
 func (dec *Decorator) AddLine(line string, meta LineMetadata) error {
-	if strings.ContainsAny(line, "\n\t\r") {
-		return errors.New("string contains one of ['\\n', '\\t', '\\r'] which aren't supported")
+	if strings.ContainsAny(line, "\n\r") {
+		return errors.New("string contains one of ['\\n', '\\r'] which aren't supported")
 	}
 
 	dec.lines = append(dec.lines, lineInfo{text: line, meta: meta})
 	return nil
 }
 
-// Adds a comment below the specified line, pointing at a specific character in that line.
-// Lines can have multiple bottom comments.
+// Adds a comment below the specified line, pointing at a specific grapheme
+// cluster (user-perceived character) in that line. Lines can have multiple
+// bottom comments.
 func (dec *Decorator) AddBottomComment(line int, at int, comment string) error {
 	if strings.ContainsAny(comment, "\n\t\r") {
 		return errors.New("string contains one of ['\\n', '\\t', '\\r'] which aren't supported")
@@ -99,8 +127,9 @@ func (dec *Decorator) AddBottomComment(line int, at int, comment string) error {
 	return nil
 }
 
-// Adds a comment above the specified line, pointing at a specific character in that line.
-// Lines can have multiple top comments.
+// Adds a comment above the specified line, pointing at a specific grapheme
+// cluster (user-perceived character) in that line. Lines can have multiple
+// top comments.
 func (dec *Decorator) AddTopComment(line int, at int, comment string) error {
 	if strings.ContainsAny(comment, "\n\t\r") {
 		return errors.New("string contains one of ['\\n', '\\t', '\\r'] which aren't supported")
@@ -150,7 +179,8 @@ func (dec *Decorator) ColourTopComment(line int, comment int, colour LineColour)
 func (dec *Decorator) String() string {
 	var b strings.Builder
 
-	// Generate prefixes and find the longest one
+	// Generate prefixes, segment lines into display clusters, and find the
+	// longest prefix
 	longestPrefixLength := 0
 	for i := 0; i < len(dec.lines); i++ {
 		line := &dec.lines[i]
@@ -158,28 +188,60 @@ func (dec *Decorator) String() string {
 		if len(line.meta.cachedPrefix) > longestPrefixLength {
 			longestPrefixLength = len(line.meta.cachedPrefix)
 		}
+		if line.clusters == nil {
+			line.clusters = segmentClusters(line.text, dec.tabWidthOrDefault())
+		}
+	}
+
+	// Reserve a left gutter wide enough for however many multi-line spans
+	// are concurrently open, and pre-render each line's slice of it
+	gutters := buildGutters(dec.multilineSpans, len(dec.lines))
+	gutterWidth := 0
+	if len(gutters) > 0 {
+		gutterWidth = len([]rune(gutters[0]))
 	}
+	blankGutter := strings.Repeat(" ", gutterWidth)
 
 	// Write out each line and its comments + colours
 	for i := 0; i < len(dec.lines); i++ {
 		line := &dec.lines[i]
 
+		if i > 0 && lineGapNeedsEllipsis(&dec.lines[i-1], line) {
+			writePrefix(&b, blankGutter, "...", longestPrefixLength)
+			b.WriteString("...\n")
+		}
+
 		// Write out top comments
-		writeTopComments(&b, line, longestPrefixLength)
+		writeTopComments(&b, line, longestPrefixLength, blankGutter, dec.layout)
 
 		// Write out line
-		writePrefix(&b, line.meta.cachedPrefix, longestPrefixLength)
-		writeColoured(&b, line.text, line.colours)
+		gutter := blankGutter
+		if gutterWidth > 0 {
+			gutter = gutters[i]
+		}
+		writePrefix(&b, gutter, line.meta.cachedPrefix, longestPrefixLength)
+		writeColoured(&b, line.clusters, line.colours)
 		b.WriteByte('\n')
 
 		// Write out bottom comments
-		writeBottomComments(&b, line, longestPrefixLength)
+		writeBottomComments(&b, line, longestPrefixLength, blankGutter, dec.layout)
 	}
 
 	return b.String()
 }
 
-func writePrefix(b *strings.Builder, prefix string, longest int) {
+// lineGapNeedsEllipsis reports whether an "..." separator belongs between
+// prev and cur: both lines must come from the same file, with at least one
+// line number skipped between them.
+func lineGapNeedsEllipsis(prev, cur *lineInfo) bool {
+	if prev.meta.FileName != cur.meta.FileName {
+		return false
+	}
+	return cur.meta.LineNumber-prev.meta.LineNumber > 1
+}
+
+func writePrefix(b *strings.Builder, gutter, prefix string, longest int) {
+	b.WriteString(gutter)
 	b.WriteString(prefix)
 	writePadding(b, longest-len(prefix))
 	b.WriteString(" | ")
@@ -191,106 +253,113 @@ func writePadding(b *strings.Builder, amount int) {
 	}
 }
 
-func writeBottomComments(b *strings.Builder, line *lineInfo, longestPrefixLength int) {
-	commentsWritten := 0
-	for j := 0; j < len(line.bottomComments)*3; j++ {
-		writePrefix(b, "", longestPrefixLength)
+// commentColumns translates each comment's cluster-index anchor into the
+// display column it falls on within the owning line.
+func commentColumns(line *lineInfo, comments []commentInfo) []int {
+	cols := make([]int, len(comments))
+	for i, comment := range comments {
+		cols[i] = columnOf(line.clusters, comment.at)
+	}
+	return cols
+}
 
-		cursor := 0
-		mod := j % 3
-		written := false
+// colourEvent marks either the start or the end of a LineColour span at a
+// particular cluster index, for the sweep in writeColoured.
+type colourEvent struct {
+	at  int
+	end bool
+	idx int
+}
 
-		for k := commentsWritten; k < len(line.bottomComments); k++ {
-			comment := line.bottomComments[k]
-			if cursor > comment.at {
-				continue
-			}
-			writePadding(b, comment.at-cursor)
-			cursor = comment.at
-
-			if k == commentsWritten && !written {
-				if mod == 0 {
-					b.WriteRune('│')
-					cursor++
-				} else if mod == 1 {
-					b.WriteByte('v')
-					cursor++
-				} else {
-					written = true // Stop the other comments from acting like they need to be written out on this line.
-					commentsWritten++
-					writeColoured(b, comment.text, comment.colours)
-					cursor += len(comment.text) // Stop the other comments from overwriting our text with their pipes.
-				}
-			} else {
-				b.WriteRune('│')
-				cursor++
-			}
+// writeColoured renders a line's grapheme clusters, applying colour to the
+// cluster ranges described by colours. Spans may overlap: leaving an inner
+// span resets to Normal and re-applies whatever outer spans are still
+// active, so e.g. a bold+red outer span survives an underlined inner span
+// nested inside it instead of staying underlined once the inner span ends.
+func writeColoured(b *strings.Builder, clusters []cluster, colours []LineColour) {
+	events := make([]colourEvent, 0, len(colours)*2)
+	for i, colour := range colours {
+		events = append(events, colourEvent{at: colour.From, idx: i})
+		events = append(events, colourEvent{at: colour.To, end: true, idx: i})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
 		}
+		// Close spans before opening new ones on the same cluster, so a span
+		// ending exactly where another begins doesn't read as overlapping.
+		return events[i].end && !events[j].end
+	})
 
-		b.WriteByte('\n')
+	var active []int // indices into colours, outermost first
+	applied := false
+	cursor := 0
+
+	applyActive := func() {
+		// SGR attributes are cumulative and there's no per-attribute "off"
+		// code, so whenever the active set changes - even just losing an
+		// inner span - we have to reset to Normal before re-applying
+		// whatever's left, or the inner span's codes bleed into the rest of
+		// the outer one.
+		if applied {
+			b.WriteString(string(Normal))
+			applied = false
+		}
+		if len(active) == 0 {
+			return
+		}
+		for _, idx := range active {
+			b.WriteString(string(colours[idx].Colour))
+		}
+		applied = true
 	}
-}
-
-func writeTopComments(b *strings.Builder, line *lineInfo, longestPrefixLength int) {
-	commentsWritten := 0
-	for j := 0; j < len(line.topComments)*3; j++ {
-		writePrefix(b, "", longestPrefixLength)
-
-		cursor := 0
-		mod := j % 3
-		written := false
 
-		for k := 0; k < len(line.topComments); k++ {
-			comment := line.topComments[k]
-			if cursor > comment.at {
-				continue
-			}
-			writePadding(b, comment.at-cursor)
-			cursor = comment.at
-
-			if k == commentsWritten && !written && mod == 0 {
-				writeColoured(b, comment.text, comment.colours)
-				written = true
-				commentsWritten++
-				cursor += len(comment.text)
-			} else if k == commentsWritten-1 && mod == 1 {
-				b.WriteByte('^')
-				cursor++
-			} else if k < commentsWritten {
-				b.WriteRune('│')
-				cursor++
+	for _, ev := range events {
+		at := ev.at
+		if at > len(clusters) {
+			at = len(clusters)
+		}
+		if at < cursor {
+			at = cursor
+		}
+		writeClusterRange(b, clusters, cursor, at)
+		cursor = at
+
+		if ev.end {
+			for i, idx := range active {
+				if idx == ev.idx {
+					active = append(active[:i], active[i+1:]...)
+					break
+				}
 			}
+		} else {
+			active = append(active, ev.idx)
 		}
+		applyActive()
+	}
 
-		b.WriteByte('\n')
+	writeClusterRange(b, clusters, cursor, len(clusters))
+	if applied {
+		b.WriteString(string(Normal))
 	}
 }
 
-func writeColoured(b *strings.Builder, text string, colours []LineColour) {
-	sort.Slice(colours, func(i, j int) bool {
-		return colours[i].From < colours[j].From
-	})
-	colourI := 0
-	start := 0
-	for colourI < len(colours) {
-		colour := colours[colourI]
-		colourI++
-
-		if start > colour.From {
-			colour.From = start // colour is a copy
-		}
-		b.WriteString(text[start:colour.From])
-		start = colour.To
-
-		b.WriteString(string(colour.Colour))
-		b.WriteString(text[colour.From:colour.To])
-		b.WriteString(Normal)
+func writeClusterRange(b *strings.Builder, clusters []cluster, from, to int) {
+	if to > len(clusters) {
+		to = len(clusters)
 	}
-	if start < len(text) {
-		b.WriteString(text[start:])
+	for i := from; i < to; i++ {
+		b.WriteString(clusters[i].String())
 	}
 }
 
+// writeColouredText is writeColoured for plain comment text, whose colours
+// are expressed in the same byte-index-as-ASCII terms comment text has
+// always used.
+func writeColouredText(b *strings.Builder, text string, colours []LineColour) {
+	writeColoured(b, segmentClusters(text, 1), colours)
+}
+
 func (meta *LineMetadata) generatePrefix() {
 	if meta.cachedPrefix != "" {
 		return
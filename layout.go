@@ -0,0 +1,374 @@
+package decorator
+
+import (
+	"sort"
+	"strings"
+)
+
+// LeaderStyle selects which glyphs the comment layout solver draws its
+// pointers and elbows with.
+type LeaderStyle int
+
+const (
+	// Box-drawing characters: '│', '╰─', '╭─'.
+	LeaderStyleUnicode LeaderStyle = iota
+	// Plain ASCII, for terminals/fonts that render box-drawing poorly:
+	// '|', '`-', ',-'.
+	LeaderStyleAscii
+)
+
+// LayoutOptions controls how AddTopComment/AddBottomComment text is laid
+// out around a line.
+type LayoutOptions struct {
+	// The total column width (measured from the start of the comment band,
+	// i.e. after the prefix/gutter) a comment's leader+text may occupy
+	// before it's word-wrapped onto additional rows. Zero means unbounded.
+	MaxWidth int
+
+	// Which glyphs to draw leaders and elbows with.
+	Style LeaderStyle
+}
+
+// SetLayoutOptions controls how comments are laid out around a line - see
+// LayoutOptions.
+func (dec *Decorator) SetLayoutOptions(opts LayoutOptions) {
+	dec.layout = opts
+}
+
+type leaderGlyphs struct {
+	pointer   string
+	elbowUp   string // connects a comment's text to a line below it (top comments)
+	elbowDown string // connects a comment's text to a line above it (bottom comments)
+}
+
+func glyphsFor(style LeaderStyle) leaderGlyphs {
+	if style == LeaderStyleAscii {
+		return leaderGlyphs{pointer: "|", elbowUp: ",- ", elbowDown: "`- "}
+	}
+	return leaderGlyphs{pointer: "│", elbowUp: "╭─ ", elbowDown: "╰─ "}
+}
+
+// layoutCell is one piece of a rendered comment row: an uncoloured glyph
+// (a pointer, an elbow, or just indentation) optionally followed by a
+// chunk of a comment's own (colourable) text.
+type layoutCell struct {
+	col     int
+	glyph   string
+	text    string
+	colours []LineColour
+}
+
+func (c layoutCell) width() int {
+	return displayWidth(c.glyph) + displayWidth(c.text)
+}
+
+// interval is a half-open column range, [from, to), occupied on a single
+// row of the comment band.
+type interval struct {
+	from, to int
+}
+
+func (iv interval) overlaps(from, to int) bool {
+	return from < iv.to && iv.from < to
+}
+
+// rowOccupancy tracks, per row, which column ranges are already spoken
+// for - by another comment's own text, or by a pointer just passing
+// through on its way to a lower row - so later placements can't cross
+// them.
+type rowOccupancy struct {
+	rows [][]interval
+}
+
+func (o *rowOccupancy) blocks(row, from, to int) bool {
+	if row >= len(o.rows) {
+		return false
+	}
+	for _, iv := range o.rows[row] {
+		if iv.overlaps(from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *rowOccupancy) reserve(row, from, to int) {
+	for len(o.rows) <= row {
+		o.rows = append(o.rows, nil)
+	}
+	o.rows[row] = append(o.rows[row], interval{from, to})
+}
+
+// layoutComments runs the comment layout solver: comments are sorted by
+// column and packed onto as few rows as they can occupy without their
+// leader+text overlapping a neighbour, the same interval-graph-colouring
+// greedy sweep assignLanes uses for span gutters. Text that doesn't fit
+// opts.MaxWidth - or that would reach another comment's column, which may
+// be a vertical rail that comment's own pointer needs later - is
+// word-wrapped onto extra rows reserved just for it.
+//
+// rows[0] is always the shared pointer row, with every comment's pointer
+// glyph at its column. rows[1:] are the rows comments ultimately place
+// their text on, with a continuation pointer standing in for any comment
+// that hasn't reached its row yet.
+func layoutComments(comments []commentInfo, cols []int, opts LayoutOptions, elbow string) [][]layoutCell {
+	glyphs := glyphsFor(opts.Style)
+	elbowWidth := displayWidth(elbow)
+
+	byColumn := make([]int, len(comments))
+	for i := range byColumn {
+		byColumn[i] = i
+	}
+	sort.SliceStable(byColumn, func(i, j int) bool {
+		return cols[byColumn[i]] < cols[byColumn[j]]
+	})
+
+	// Word-wrap each comment to whichever is narrower: opts.MaxWidth, or
+	// the gap to the next comment's column. The latter bound is what
+	// stops one comment's text from ever reaching another's column - an
+	// unbounded comment could otherwise swallow a neighbour's anchor
+	// entirely, which no amount of row-juggling can route a pointer
+	// around.
+	chunks := make([][]string, len(comments))
+	for pos, i := range byColumn {
+		avail := 0
+		bounded := false
+		if opts.MaxWidth > 0 {
+			avail = opts.MaxWidth - cols[i] - elbowWidth
+			bounded = true
+		}
+		if pos+1 < len(byColumn) {
+			gap := cols[byColumn[pos+1]] - cols[i] - elbowWidth
+			if !bounded || gap < avail {
+				avail = gap
+				bounded = true
+			}
+		}
+		if !bounded {
+			// Nothing actually bounds this comment - no MaxWidth, no
+			// comment to its right - so leave it unwrapped rather than
+			// clamping to a width of 1 and shredding it one word per row.
+			chunks[i] = wordWrap(comments[i].text, 0)
+			continue
+		}
+		if avail < 1 {
+			avail = 1
+		}
+		chunks[i] = wordWrap(comments[i].text, avail)
+	}
+
+	// Place the most constrained comments first - the ones needing the
+	// most rows, then the widest - so a narrow, single-row comment ends
+	// up yielding its row to a wider or taller neighbour's pass-through
+	// rather than greedily claiming row 1 and leaving nowhere for that
+	// neighbour's pointer to go.
+	order := make([]int, len(comments))
+	for i := range order {
+		order[i] = i
+	}
+	chunkWidth := func(i int) int {
+		w := 0
+		for _, c := range chunks[i] {
+			if cw := displayWidth(c); cw > w {
+				w = cw
+			}
+		}
+		return w
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if len(chunks[a]) != len(chunks[b]) {
+			return len(chunks[a]) > len(chunks[b])
+		}
+		if wa, wb := chunkWidth(a), chunkWidth(b); wa != wb {
+			return wa > wb
+		}
+		return cols[a] < cols[b]
+	})
+
+	startRow := make([]int, len(comments))
+	var occupied rowOccupancy
+
+	// maxAttempts bounds the row search so a pathological case - e.g. an
+	// unsplittable word wide enough to straddle a neighbour's column no
+	// matter the row - can't spin forever; once the attempts run out the
+	// comment is placed on the row after the last one tried, without
+	// checking that row, rather than hang.
+	maxAttempts := len(comments) + 2
+
+	for _, i := range order {
+		at := cols[i]
+		cs := chunks[i]
+
+		row := 1
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			blocked := false
+			for r := 1; r < row; r++ {
+				if occupied.blocks(r, at, at+1) {
+					blocked = true
+					break
+				}
+			}
+			if !blocked {
+				for k, chunk := range cs {
+					end := at + elbowWidth + displayWidth(chunk)
+					if occupied.blocks(row+k, at, end) {
+						blocked = true
+						break
+					}
+				}
+			}
+			if !blocked {
+				break
+			}
+			row++
+		}
+
+		startRow[i] = row
+		for r := 1; r < row; r++ {
+			occupied.reserve(r, at, at+1)
+		}
+		for k, chunk := range cs {
+			occupied.reserve(row+k, at, at+elbowWidth+displayWidth(chunk))
+		}
+	}
+
+	numRows := 1
+	for i := range comments {
+		if end := startRow[i] + len(chunks[i]); end > numRows {
+			numRows = end
+		}
+	}
+
+	rows := make([][]layoutCell, numRows)
+	for i := range comments {
+		rows[0] = append(rows[0], layoutCell{col: cols[i], glyph: glyphs.pointer})
+	}
+
+	continuationIndent := strings.Repeat(" ", elbowWidth)
+	if elbowWidth > 0 {
+		continuationIndent = glyphs.pointer + strings.Repeat(" ", elbowWidth-displayWidth(glyphs.pointer))
+	}
+
+	for i := range comments {
+		for row := 1; row < startRow[i]; row++ {
+			rows[row] = append(rows[row], layoutCell{col: cols[i], glyph: glyphs.pointer})
+		}
+
+		for k, chunk := range chunks[i] {
+			glyph := elbow
+			if k > 0 {
+				glyph = continuationIndent
+			}
+			rows[startRow[i]+k] = append(rows[startRow[i]+k], layoutCell{
+				col:     cols[i],
+				glyph:   glyph,
+				text:    chunk,
+				colours: comments[i].colours,
+			})
+		}
+	}
+
+	for _, row := range rows {
+		sort.Slice(row, func(i, j int) bool { return row[i].col < row[j].col })
+	}
+
+	return rows
+}
+
+// wordWrap splits text into chunks no wider than maxWidth columns, breaking
+// on whitespace. A maxWidth of zero or less leaves text unwrapped, and a
+// single word wider than maxWidth is left to overflow rather than being
+// split mid-word.
+func wordWrap(text string, maxWidth int) []string {
+	if maxWidth <= 0 || displayWidth(text) <= maxWidth {
+		return []string{text}
+	}
+
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{text}
+	}
+
+	var chunks []string
+	var cur strings.Builder
+	curWidth := 0
+
+	flush := func() {
+		if cur.Len() > 0 {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+			curWidth = 0
+		}
+	}
+
+	for _, word := range words {
+		wordWidth := displayWidth(word)
+		sep := 0
+		if cur.Len() > 0 {
+			sep = 1
+		}
+		if curWidth+sep+wordWidth > maxWidth && cur.Len() > 0 {
+			flush()
+			sep = 0
+		}
+		if sep == 1 {
+			cur.WriteByte(' ')
+			curWidth++
+		}
+		cur.WriteString(word)
+		curWidth += wordWidth
+	}
+	flush()
+
+	return chunks
+}
+
+func writeLayoutRow(b *strings.Builder, row []layoutCell, gutter string, longestPrefixLength int) {
+	writePrefix(b, gutter, "", longestPrefixLength)
+
+	cursor := 0
+	for _, cell := range row {
+		if cell.col > cursor {
+			writePadding(b, cell.col-cursor)
+			cursor = cell.col
+		}
+		b.WriteString(cell.glyph)
+		if cell.text != "" {
+			writeColouredText(b, cell.text, cell.colours)
+		}
+		cursor += cell.width()
+	}
+
+	b.WriteByte('\n')
+}
+
+func writeBottomComments(b *strings.Builder, line *lineInfo, longestPrefixLength int, blankGutter string, layout LayoutOptions) {
+	if len(line.bottomComments) == 0 {
+		return
+	}
+
+	cols := commentColumns(line, line.bottomComments)
+	rows := layoutComments(line.bottomComments, cols, layout, glyphsFor(layout.Style).elbowDown)
+
+	for _, row := range rows {
+		writeLayoutRow(b, row, blankGutter, longestPrefixLength)
+	}
+}
+
+func writeTopComments(b *strings.Builder, line *lineInfo, longestPrefixLength int, blankGutter string, layout LayoutOptions) {
+	if len(line.topComments) == 0 {
+		return
+	}
+
+	cols := commentColumns(line, line.topComments)
+	rows := layoutComments(line.topComments, cols, layout, glyphsFor(layout.Style).elbowUp)
+
+	// Text rows read top-to-bottom same as bottom comments, but the shared
+	// pointer row (rows[0]) belongs right next to the line, not above
+	// everything else, so it's emitted last.
+	for _, row := range rows[1:] {
+		writeLayoutRow(b, row, blankGutter, longestPrefixLength)
+	}
+	writeLayoutRow(b, rows[0], blankGutter, longestPrefixLength)
+}
@@ -0,0 +1,61 @@
+package decorator
+
+import "testing"
+
+// cellsDontOverlap fails the test if any two cells in a row claim
+// intersecting columns - the exact failure mode layoutComments must avoid
+// when comments' anchors and text overlap.
+func cellsDontOverlap(t *testing.T, rows [][]layoutCell) {
+	t.Helper()
+	for r, row := range rows {
+		for i := 1; i < len(row); i++ {
+			prevEnd := row[i-1].col + row[i-1].width()
+			if row[i].col < prevEnd {
+				t.Errorf("row %d: cell at col %d (%q) overlaps previous cell ending at col %d",
+					r, row[i].col, row[i].glyph+row[i].text, prevEnd)
+			}
+		}
+	}
+}
+
+func TestLayoutCommentsNoOverlap(t *testing.T) {
+	comments := []commentInfo{
+		{at: 0, text: "alpha"},
+		{at: 0, text: "beta overlapping"},
+		{at: 0, text: "gamma"},
+	}
+	cols := []int{2, 4, 15}
+
+	rows := layoutComments(comments, cols, LayoutOptions{}, glyphsFor(LeaderStyleUnicode).elbowDown)
+	cellsDontOverlap(t, rows)
+
+	if len(rows) < 3 {
+		t.Fatalf("expected comments with overlapping columns to need more than 1 text row below the pointer row, got %d rows", len(rows))
+	}
+}
+
+func TestLayoutCommentsSingleNoConflict(t *testing.T) {
+	comments := []commentInfo{{at: 0, text: "hello"}}
+	cols := []int{3}
+
+	rows := layoutComments(comments, cols, LayoutOptions{}, glyphsFor(LeaderStyleUnicode).elbowDown)
+	cellsDontOverlap(t, rows)
+
+	if len(rows) != 2 {
+		t.Fatalf("a single comment should only need the pointer row plus its own text row, got %d rows", len(rows))
+	}
+}
+
+func TestWordWrap(t *testing.T) {
+	got := wordWrap("beta overlapping", 8)
+	want := []string{"beta", "overlapping"}
+
+	if len(got) != len(want) {
+		t.Fatalf("wordWrap chunks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("wordWrap chunks = %v, want %v", got, want)
+		}
+	}
+}
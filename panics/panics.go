@@ -0,0 +1,233 @@
+// Package panics turns a recovered panic, or a wrapped error chain, into an
+// annotated source snippet via the decorator package - the same shape of
+// output a compiler diagnostic gives you, but pointed at your own stack
+// trace.
+package panics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/BradleyChatha/decorator"
+)
+
+// Controls how FromPanic extracts and renders source context for each
+// frame of a recovered panic's stack trace.
+type PanicOptions struct {
+	// How many lines of source to show above and below the reported line
+	// in each frame. Defaults to 2 if left at zero.
+	ContextLines int
+
+	// Styles applied to frames based on which kind of code they belong to.
+	// A zero Style leaves the frame uncoloured.
+	RuntimeStyle decorator.Style
+	StdlibStyle  decorator.Style
+	UserStyle    decorator.Style
+}
+
+// frameKind classifies a stack frame by where its code lives, so FromPanic
+// can colour frames consistently.
+type frameKind int
+
+const (
+	frameUser frameKind = iota
+	frameStdlib
+	frameRuntime
+)
+
+type frame struct {
+	function string
+	file     string
+	line     int
+	kind     frameKind
+}
+
+var (
+	goroutineRE = regexp.MustCompile(`^goroutine (\d+) `)
+	frameLocRE  = regexp.MustCompile(`^\t(.+):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// sanitizer replaces every character AddLine/AddBottomComment/AddTopComment
+// reject - '\n', '\r', and (for comments) '\t' - with a space, in text that
+// might contain any of them, such as a panic value or an error's message.
+var sanitizer = strings.NewReplacer("\n", " ", "\r", " ", "\t", " ")
+
+// FromPanic parses a runtime.Stack-formatted trace (as produced by
+// debug.Stack, or handed to a deferred recover) and builds a Decorator
+// showing the real source lines around each frame, the panicking call site
+// named in a top comment above its frame, and the panic value plus
+// goroutine id as bottom comments under the final frame.
+func FromPanic(rvr any, stack []byte, opts PanicOptions) *decorator.Decorator {
+	if opts.ContextLines <= 0 {
+		opts.ContextLines = 2
+	}
+
+	frames, goroutineID := parseStack(stack)
+	dec := &decorator.Decorator{}
+	lineIndex := 0
+	lastLine := -1
+
+	for _, f := range frames {
+		srcLines, firstLineNumber, err := readContext(f.file, f.line, opts.ContextLines)
+		if err != nil {
+			continue
+		}
+
+		style := styleFor(f.kind, opts)
+		frameStart := lineIndex
+
+		for i, text := range srcLines {
+			meta := decorator.LineMetadata{FileName: f.file, LineNumber: firstLineNumber + i}
+			if err := dec.AddLine(text, meta); err != nil {
+				continue
+			}
+
+			lineNumber := firstLineNumber + i
+			if lineNumber == f.line && len(style) > 0 {
+				dec.ColourLine(lineIndex, decorator.LineColour{From: 0, To: len([]rune(text)), Colour: style.Compose()})
+			}
+
+			lastLine = lineIndex
+			lineIndex++
+		}
+
+		if lineIndex > frameStart {
+			dec.AddTopComment(frameStart, 0, f.function)
+		}
+	}
+
+	if lastLine >= 0 {
+		dec.AddBottomComment(lastLine, 0, sanitizer.Replace(fmt.Sprintf("panic: %v", rvr)))
+		if goroutineID != "" {
+			dec.AddBottomComment(lastLine, 0, sanitizer.Replace(fmt.Sprintf("goroutine %s", goroutineID)))
+		}
+	}
+
+	return dec
+}
+
+// FromError walks err's Unwrap chain and renders each error in the chain as
+// its own line, with the message of whatever it wraps attached as a bottom
+// comment, so the full chain reads top (outermost) to bottom (root cause).
+func FromError(err error) *decorator.Decorator {
+	dec := &decorator.Decorator{}
+
+	lineIndex := 0
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		msg := sanitizer.Replace(e.Error())
+		if addErr := dec.AddLine(msg, decorator.LineMetadata{FileName: "error", LineNumber: lineIndex + 1}); addErr != nil {
+			continue
+		}
+
+		if inner := errors.Unwrap(e); inner != nil {
+			dec.AddBottomComment(lineIndex, 0, fmt.Sprintf("wraps: %s", sanitizer.Replace(inner.Error())))
+		}
+
+		lineIndex++
+	}
+
+	return dec
+}
+
+// parseStack extracts each call frame and the goroutine id from a
+// runtime.Stack-formatted trace. Frames that don't resolve to a
+// "file:line" pair (e.g. inlined or elided frames) are skipped.
+func parseStack(stack []byte) (frames []frame, goroutineID string) {
+	lines := strings.Split(string(stack), "\n")
+	if len(lines) > 0 {
+		if m := goroutineRE.FindStringSubmatch(lines[0]); m != nil {
+			goroutineID = m[1]
+		}
+	}
+
+	for i := 1; i+1 < len(lines); i += 2 {
+		function := strings.TrimSpace(lines[i])
+		if function == "" {
+			continue
+		}
+
+		m := frameLocRE.FindStringSubmatch(lines[i+1])
+		if m == nil {
+			i--
+			continue
+		}
+
+		lineNumber, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+
+		frames = append(frames, frame{
+			function: function,
+			file:     m[1],
+			line:     lineNumber,
+			kind:     classify(function, m[1]),
+		})
+	}
+
+	return frames, goroutineID
+}
+
+func classify(function, file string) frameKind {
+	switch {
+	case strings.HasPrefix(function, "runtime."), strings.Contains(file, "/src/runtime/"):
+		return frameRuntime
+	case isStdlibFile(file):
+		return frameStdlib
+	default:
+		return frameUser
+	}
+}
+
+func isStdlibFile(file string) bool {
+	root := runtime.GOROOT()
+	return root != "" && strings.HasPrefix(file, root+"/src/")
+}
+
+func styleFor(kind frameKind, opts PanicOptions) decorator.Style {
+	switch kind {
+	case frameRuntime:
+		return opts.RuntimeStyle
+	case frameStdlib:
+		return opts.StdlibStyle
+	default:
+		return opts.UserStyle
+	}
+}
+
+// readContext reads the lines from [line-context, line+context] (1-indexed,
+// clamped to the start of the file) out of the file at path.
+func readContext(path string, line, context int) (lines []string, firstLine int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	from := line - context
+	if from < 1 {
+		from = 1
+	}
+	to := line + context
+
+	scanner := bufio.NewScanner(f)
+	current := 0
+	for scanner.Scan() {
+		current++
+		if current < from {
+			continue
+		}
+		if current > to {
+			break
+		}
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines, from, scanner.Err()
+}
@@ -0,0 +1,322 @@
+package decorator
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Renderer is implemented by anything that can turn a Decorator's lines,
+// colours and comments into a specific output format. *Decorator satisfies
+// this directly.
+type Renderer interface {
+	RenderPlain(w io.Writer) error
+	RenderANSI(w io.Writer, opts ANSIOptions) error
+	RenderHTML(w io.Writer, opts HTMLOptions) error
+	RenderJSON(w io.Writer) error
+}
+
+var _ Renderer = (*Decorator)(nil)
+
+// ANSIOptions controls RenderANSI. It has no fields yet; it exists so
+// ANSI-specific knobs can be added later without breaking callers.
+type ANSIOptions struct{}
+
+// RenderANSI writes dec to w exactly as String() would - the full output
+// with ANSI escape codes for colour.
+func (dec *Decorator) RenderANSI(w io.Writer, opts ANSIOptions) error {
+	_, err := io.WriteString(w, dec.String())
+	return err
+}
+
+var ansiCodeRE = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// RenderPlain writes dec to w with every ANSI escape code stripped, for
+// output that's piped to a file, a CI log parser, or anywhere else ANSI
+// codes would just show up as garbage.
+func (dec *Decorator) RenderPlain(w io.Writer) error {
+	_, err := io.WriteString(w, ansiCodeRE.ReplaceAllString(dec.String(), ""))
+	return err
+}
+
+// AutoRenderer writes dec to w, using RenderANSI when w looks like an
+// interactive terminal and RenderPlain otherwise - the same TTY-detection
+// pattern libraries like go-isatty/go-colorable use to avoid leaking raw
+// escape codes into piped output.
+func (dec *Decorator) AutoRenderer(w io.Writer) error {
+	if f, ok := w.(*os.File); ok && isTerminal(f) {
+		return dec.RenderANSI(w, ANSIOptions{})
+	}
+	return dec.RenderPlain(w)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// HTMLOptions controls RenderHTML.
+type HTMLOptions struct {
+	// If true, the output is preceded by a <style> block containing CSS()
+	// and wrapped in a <pre class="decorator"> element, making it a
+	// drop-in, self-contained fragment.
+	Standalone bool
+}
+
+// ansiClassNames maps the package's named attribute/colour constants to the
+// CSS class RenderHTML emits for them. Styles built from Fg256/Bg256/
+// FgRGB/BgRGB instead fall back to a generated "dec-code-..." class that
+// CSS() doesn't define.
+var ansiClassNames = map[LineColourEnum]string{
+	Bold:          "dec-bold",
+	Dim:           "dec-dim",
+	Italic:        "dec-italic",
+	Underline:     "dec-underline",
+	Reverse:       "dec-reverse",
+	Strikethrough: "dec-strikethrough",
+	FgBlack:       "dec-fg-black",
+	FgRed:         "dec-fg-red",
+	FgGreen:       "dec-fg-green",
+	FgYellow:      "dec-fg-yellow",
+	FgBlue:        "dec-fg-blue",
+	FgMagenta:     "dec-fg-magenta",
+	FgCyan:        "dec-fg-cyan",
+	FgWhite:       "dec-fg-white",
+	BgBlack:       "dec-bg-black",
+	BgRed:         "dec-bg-red",
+	BgGreen:       "dec-bg-green",
+	BgYellow:      "dec-bg-yellow",
+	BgBlue:        "dec-bg-blue",
+	BgMagenta:     "dec-bg-magenta",
+	BgCyan:        "dec-bg-cyan",
+	BgWhite:       "dec-bg-white",
+}
+
+func classForCode(code string) string {
+	if class, ok := ansiClassNames[LineColourEnum(code)]; ok {
+		return class
+	}
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(code, "\x1b["), "m")
+	return "dec-code-" + strings.ReplaceAll(trimmed, ";", "-")
+}
+
+func classesForColour(colour LineColourEnum) []string {
+	codes := ansiCodeRE.FindAllString(string(colour), -1)
+	if len(codes) == 0 {
+		return nil
+	}
+	classes := make([]string, len(codes))
+	for i, code := range codes {
+		classes[i] = classForCode(code)
+	}
+	return classes
+}
+
+// CSS returns a stylesheet defining every class RenderHTML emits for the
+// named 16-colour/attribute palette. It doesn't cover the "dec-code-..."
+// classes generated for Fg256/Bg256/FgRGB/BgRGB styles - style those
+// yourself, or avoid them when rendering to HTML.
+func CSS() string {
+	var b strings.Builder
+	b.WriteString(".dec-bold { font-weight: bold; }\n")
+	b.WriteString(".dec-dim { opacity: 0.7; }\n")
+	b.WriteString(".dec-italic { font-style: italic; }\n")
+	b.WriteString(".dec-underline { text-decoration: underline; }\n")
+	b.WriteString(".dec-reverse { filter: invert(1); }\n")
+	b.WriteString(".dec-strikethrough { text-decoration: line-through; }\n")
+	b.WriteString(".dec-comment-anchor { opacity: 0.6; }\n")
+
+	names := []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+	hexes := []string{"#000000", "#cc0000", "#4e9a06", "#c4a000", "#3465a4", "#75507b", "#06989a", "#d3d7cf"}
+	for i, name := range names {
+		b.WriteString(".dec-fg-" + name + " { color: " + hexes[i] + "; }\n")
+		b.WriteString(".dec-bg-" + name + " { background-color: " + hexes[i] + "; }\n")
+	}
+	return b.String()
+}
+
+// RenderHTML writes dec to w as HTML, wrapping coloured spans in
+// <span class="..."> elements keyed by semantic style names (see CSS).
+// Nested LineColour spans become nested <span> tags. Each "</span>" closes
+// the innermost open tag, so spans that partially overlap without nesting
+// still produce balanced tags, but the text after the first of them ends
+// carries the classes of the span that opened first rather than the one
+// still open.
+func (dec *Decorator) RenderHTML(w io.Writer, opts HTMLOptions) error {
+	longestPrefixLength := 0
+	for i := range dec.lines {
+		line := &dec.lines[i]
+		line.meta.generatePrefix()
+		if len(line.meta.cachedPrefix) > longestPrefixLength {
+			longestPrefixLength = len(line.meta.cachedPrefix)
+		}
+		if line.clusters == nil {
+			line.clusters = segmentClusters(line.text, dec.tabWidthOrDefault())
+		}
+	}
+
+	var b strings.Builder
+	if opts.Standalone {
+		b.WriteString("<style>\n")
+		b.WriteString(CSS())
+		b.WriteString("</style>\n<pre class=\"decorator\">\n")
+	}
+
+	for i := range dec.lines {
+		line := &dec.lines[i]
+
+		writeHTMLComments(&b, line.topComments, commentColumns(line, line.topComments), longestPrefixLength)
+
+		b.WriteString(html.EscapeString(line.meta.cachedPrefix))
+		writePadding(&b, longestPrefixLength-len(line.meta.cachedPrefix))
+		b.WriteString(" | ")
+		writeHTMLClusters(&b, line.clusters, line.colours)
+		b.WriteByte('\n')
+
+		writeHTMLComments(&b, line.bottomComments, commentColumns(line, line.bottomComments), longestPrefixLength)
+	}
+
+	if opts.Standalone {
+		b.WriteString("</pre>\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeHTMLClusters(b *strings.Builder, clusters []cluster, colours []LineColour) {
+	events := make([]colourEvent, 0, len(colours)*2)
+	for i, c := range colours {
+		events = append(events, colourEvent{at: c.From, idx: i})
+		events = append(events, colourEvent{at: c.To, end: true, idx: i})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		if events[i].end != events[j].end {
+			return events[i].end
+		}
+		// Of spans opening on the same cluster, open the longest first so
+		// its tag encloses the shorter ones.
+		if !events[i].end {
+			return colours[events[i].idx].To > colours[events[j].idx].To
+		}
+		return false
+	})
+
+	cursor := 0
+	for _, ev := range events {
+		at := ev.at
+		if at > len(clusters) {
+			at = len(clusters)
+		}
+		if at < cursor {
+			at = cursor
+		}
+		writeHTMLClusterRange(b, clusters, cursor, at)
+		cursor = at
+
+		if ev.end {
+			b.WriteString("</span>")
+			continue
+		}
+		b.WriteString(`<span class="`)
+		b.WriteString(strings.Join(classesForColour(colours[ev.idx].Colour), " "))
+		b.WriteString(`">`)
+	}
+	writeHTMLClusterRange(b, clusters, cursor, len(clusters))
+}
+
+func writeHTMLClusterRange(b *strings.Builder, clusters []cluster, from, to int) {
+	if to > len(clusters) {
+		to = len(clusters)
+	}
+	for i := from; i < to; i++ {
+		b.WriteString(html.EscapeString(clusters[i].String()))
+	}
+}
+
+// writeHTMLComments renders each comment on its own line: padding in place
+// of the prefix, " | ", indentation to its anchor column (commentColumns'
+// display column, the same position the ANSI/plain leader points at), then
+// a '^' in a dec-comment-anchor span followed by the comment text. No
+// multi-row leader tree is drawn and comments are not word-wrapped.
+func writeHTMLComments(b *strings.Builder, comments []commentInfo, cols []int, longestPrefixLength int) {
+	for i, comment := range comments {
+		writePadding(b, longestPrefixLength)
+		b.WriteString(" | ")
+		writePadding(b, cols[i])
+		b.WriteString(`<span class="dec-comment-anchor">^</span> <span class="dec-comment">`)
+		b.WriteString(html.EscapeString(comment.text))
+		b.WriteString("</span>\n")
+	}
+}
+
+// The JSON schema RenderJSON emits.
+type jsonSpan struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Style string `json:"style"`
+}
+
+type jsonComment struct {
+	At   int    `json:"at"`
+	Text string `json:"text"`
+}
+
+type jsonLine struct {
+	Prefix         string        `json:"prefix"`
+	Text           string        `json:"text"`
+	Spans          []jsonSpan    `json:"spans"`
+	TopComments    []jsonComment `json:"topComments"`
+	BottomComments []jsonComment `json:"bottomComments"`
+}
+
+type jsonDocument struct {
+	Lines []jsonLine `json:"lines"`
+}
+
+// RenderJSON writes dec to w as a JSON document suitable for programmatic
+// consumption, rather than terminal rendering.
+func (dec *Decorator) RenderJSON(w io.Writer) error {
+	doc := jsonDocument{Lines: make([]jsonLine, len(dec.lines))}
+
+	for i := range dec.lines {
+		line := &dec.lines[i]
+		line.meta.generatePrefix()
+
+		doc.Lines[i] = jsonLine{
+			Prefix:         line.meta.cachedPrefix,
+			Text:           line.text,
+			Spans:          spansToJSON(line.colours),
+			TopComments:    commentsToJSON(line.topComments),
+			BottomComments: commentsToJSON(line.bottomComments),
+		}
+	}
+
+	return json.NewEncoder(w).Encode(doc)
+}
+
+func spansToJSON(colours []LineColour) []jsonSpan {
+	spans := make([]jsonSpan, len(colours))
+	for i, c := range colours {
+		spans[i] = jsonSpan{From: c.From, To: c.To, Style: string(c.Colour)}
+	}
+	return spans
+}
+
+func commentsToJSON(comments []commentInfo) []jsonComment {
+	out := make([]jsonComment, len(comments))
+	for i, c := range comments {
+		out[i] = jsonComment{At: c.at, Text: c.text}
+	}
+	return out
+}
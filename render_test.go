@@ -0,0 +1,69 @@
+package decorator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestDecorator(t *testing.T) *Decorator {
+	t.Helper()
+	var d Decorator
+	if err := d.AddLine("0123456789", LineMetadata{FileName: "f", LineNumber: 1}); err != nil {
+		t.Fatalf("AddLine: %v", err)
+	}
+	d.ColourLine(0, LineColour{From: 2, To: 5, Colour: FgMagenta})
+	if err := d.AddBottomComment(0, 0, "a comment"); err != nil {
+		t.Fatalf("AddBottomComment: %v", err)
+	}
+	return &d
+}
+
+func TestRenderPlainStripsANSI(t *testing.T) {
+	d := newTestDecorator(t)
+
+	var buf bytes.Buffer
+	if err := d.RenderPlain(&buf); err != nil {
+		t.Fatalf("RenderPlain: %v", err)
+	}
+
+	got := buf.String()
+	if strings.Contains(got, "\x1b[") {
+		t.Fatalf("RenderPlain left an ANSI escape in the output: %q", got)
+	}
+	if !strings.Contains(got, "0123456789") {
+		t.Fatalf("RenderPlain dropped the line text: %q", got)
+	}
+	if !strings.Contains(got, "a comment") {
+		t.Fatalf("RenderPlain dropped the comment text: %q", got)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	d := newTestDecorator(t)
+
+	var buf bytes.Buffer
+	if err := d.RenderJSON(&buf); err != nil {
+		t.Fatalf("RenderJSON: %v", err)
+	}
+
+	var doc jsonDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("RenderJSON produced invalid JSON: %v", err)
+	}
+
+	if len(doc.Lines) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(doc.Lines))
+	}
+	line := doc.Lines[0]
+	if line.Text != "0123456789" {
+		t.Errorf("Text = %q, want %q", line.Text, "0123456789")
+	}
+	if len(line.Spans) != 1 || line.Spans[0].From != 2 || line.Spans[0].To != 5 {
+		t.Errorf("unexpected spans: %+v", line.Spans)
+	}
+	if len(line.BottomComments) != 1 || line.BottomComments[0].Text != "a comment" {
+		t.Errorf("unexpected bottom comments: %+v", line.BottomComments)
+	}
+}
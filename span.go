@@ -0,0 +1,176 @@
+package decorator
+
+import (
+	"errors"
+	"sort"
+)
+
+// AnnotationPos controls whether an AnnotateSpan message renders as a top
+// comment anchored at the span's start, or a bottom comment anchored at
+// the span's end.
+type AnnotationPos int
+
+const (
+	AnnotationAbove AnnotationPos = iota
+	AnnotationBelow
+)
+
+// spanLineEnd is a deliberately out-of-range cluster index used to mean
+// "to the end of the line" when highlighting a span's first/middle/last
+// lines, relying on the same clamping writeColoured already does for
+// out-of-range LineColour.To values.
+const spanLineEnd = 1 << 30
+
+// A Span describes a range of source spanning one or more lines, given as
+// line indices (as passed to AddLine) and grapheme-cluster columns within
+// those lines.
+type Span struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+}
+
+func (dec *Decorator) checkSpan(span Span) error {
+	if span.StartLine < 0 || span.EndLine >= len(dec.lines) {
+		return errors.New("span line index out of bounds")
+	}
+	if span.StartLine > span.EndLine {
+		return errors.New("span start line must not be after its end line")
+	}
+	return nil
+}
+
+// AnnotateSpan attaches message to a (possibly multi-line) span. Single-line
+// spans render exactly like AddTopComment/AddBottomComment. Multi-line spans
+// additionally reserve a connecting gutter - drawn with '╭', '│' and '╰' -
+// linking the span's first and last line, with message anchored at whichever
+// end position selects.
+func (dec *Decorator) AnnotateSpan(span Span, message string, style Style, position AnnotationPos) error {
+	if err := dec.checkSpan(span); err != nil {
+		return err
+	}
+
+	if span.StartLine != span.EndLine {
+		dec.multilineSpans = append(dec.multilineSpans, span)
+	}
+
+	if position == AnnotationAbove {
+		if err := dec.AddTopComment(span.StartLine, span.StartCol, message); err != nil {
+			return err
+		}
+		comments := dec.lines[span.StartLine].topComments
+		if len(style) > 0 {
+			comments[len(comments)-1].colours = append(comments[len(comments)-1].colours, LineColour{From: 0, To: spanLineEnd, Colour: style.Compose()})
+		}
+		return nil
+	}
+
+	if err := dec.AddBottomComment(span.EndLine, span.EndCol, message); err != nil {
+		return err
+	}
+	comments := dec.lines[span.EndLine].bottomComments
+	if len(style) > 0 {
+		comments[len(comments)-1].colours = append(comments[len(comments)-1].colours, LineColour{From: 0, To: spanLineEnd, Colour: style.Compose()})
+	}
+	return nil
+}
+
+// HighlightSpan colours every cluster covered by span. Single-line spans
+// colour just that range; multi-line spans colour from StartCol to the end
+// of the first line, the whole of every line in between, and from the start
+// of the last line up to EndCol - and reserve the same connecting gutter
+// AnnotateSpan does.
+func (dec *Decorator) HighlightSpan(span Span, colour LineColourEnum) error {
+	if err := dec.checkSpan(span); err != nil {
+		return err
+	}
+
+	if span.StartLine == span.EndLine {
+		return dec.ColourLine(span.StartLine, LineColour{From: span.StartCol, To: span.EndCol, Colour: colour})
+	}
+
+	dec.multilineSpans = append(dec.multilineSpans, span)
+
+	dec.ColourLine(span.StartLine, LineColour{From: span.StartCol, To: spanLineEnd, Colour: colour})
+	for line := span.StartLine + 1; line < span.EndLine; line++ {
+		dec.ColourLine(line, LineColour{From: 0, To: spanLineEnd, Colour: colour})
+	}
+	dec.ColourLine(span.EndLine, LineColour{From: 0, To: span.EndCol, Colour: colour})
+	return nil
+}
+
+// assignLanes greedily packs spans into the fewest left-gutter lanes such
+// that two spans only ever share a lane if their line ranges don't overlap,
+// the same interval-graph-colouring approach the layout solver uses for
+// comment rows. It returns each span's lane and the total lane count, which
+// becomes the reserved gutter width.
+func assignLanes(spans []Span) ([]int, int) {
+	lanes := make([]int, len(spans))
+	busyUntil := make([]int, 0, len(spans))
+
+	order := make([]int, len(spans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return spans[order[i]].StartLine < spans[order[j]].StartLine
+	})
+
+	for _, i := range order {
+		span := spans[i]
+		placed := false
+		for lane, until := range busyUntil {
+			if until < span.StartLine {
+				busyUntil[lane] = span.EndLine
+				lanes[i] = lane
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			lanes[i] = len(busyUntil)
+			busyUntil = append(busyUntil, span.EndLine)
+		}
+	}
+
+	return lanes, len(busyUntil)
+}
+
+// buildGutters renders, for every line index in [0, lineCount), the
+// connecting glyphs every multi-line span occupies on that line.
+func buildGutters(spans []Span, lineCount int) []string {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	lanes, laneCount := assignLanes(spans)
+	rows := make([][]rune, lineCount)
+	for i := range rows {
+		row := make([]rune, laneCount)
+		for j := range row {
+			row[j] = ' '
+		}
+		rows[i] = row
+	}
+
+	for i, span := range spans {
+		lane := lanes[i]
+		for line := span.StartLine; line <= span.EndLine; line++ {
+			switch line {
+			case span.StartLine:
+				rows[line][lane] = '╭'
+			case span.EndLine:
+				rows[line][lane] = '╰'
+			default:
+				rows[line][lane] = '│'
+			}
+		}
+	}
+
+	gutters := make([]string, lineCount)
+	for i, row := range rows {
+		gutters[i] = string(row)
+	}
+	return gutters
+}
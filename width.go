@@ -0,0 +1,133 @@
+package decorator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// zeroWidthJoiner glues adjacent emoji runes into a single rendered glyph,
+// e.g. the components of a family or profession emoji.
+const zeroWidthJoiner = '‍'
+
+// A cluster is a single user-perceived character: a base rune plus any
+// combining marks or zero-width joins that render together with it, along
+// with the number of terminal columns it occupies once tabs are expanded.
+type cluster struct {
+	runes []rune
+	width int
+}
+
+func (c cluster) String() string {
+	if len(c.runes) == 1 && c.runes[0] == '\t' {
+		return strings.Repeat(" ", c.width)
+	}
+	return string(c.runes)
+}
+
+func isCombiningMark(r rune) bool {
+	return unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Cf, r)
+}
+
+// runeWidth approximates the number of terminal columns a single rune
+// occupies, outside of any clustering or tab expansion.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0:
+		return 0
+	case isCombiningMark(r):
+		return 0
+	case isWideRune(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// isWideRune reports whether r belongs to a block that terminals typically
+// render two columns wide, approximating the East Asian Wide/Fullwidth
+// categories from UAX #11.
+func isWideRune(r rune) bool {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r == 0x2329, r == 0x232A,
+		r >= 0x2E80 && r <= 0x303E,   // CJK Radicals .. CJK Symbols
+		r >= 0x3041 && r <= 0x33FF,   // Hiragana .. CJK Compat
+		r >= 0x3400 && r <= 0x4DBF,   // CJK Unified Ideographs Ext A
+		r >= 0x4E00 && r <= 0x9FFF,   // CJK Unified Ideographs
+		r >= 0xA000 && r <= 0xA4CF,   // Yi
+		r >= 0xAC00 && r <= 0xD7A3,   // Hangul Syllables
+		r >= 0xF900 && r <= 0xFAFF,   // CJK Compatibility Ideographs
+		r >= 0xFE30 && r <= 0xFE4F,   // CJK Compatibility Forms
+		r >= 0xFF00 && r <= 0xFF60,   // Fullwidth Forms
+		r >= 0xFFE0 && r <= 0xFFE6,   // Fullwidth Signs
+		r >= 0x20000 && r <= 0x2FFFD, // CJK Unified Ideographs Ext B..F
+		r >= 0x30000 && r <= 0x3FFFD,
+		r >= 0x1F300 && r <= 0x1FAFF: // Emoji blocks
+		return true
+	default:
+		return false
+	}
+}
+
+// segmentClusters splits text into display clusters, expanding any tabs it
+// finds to the next tabWidth-aligned stop as it goes.
+func segmentClusters(text string, tabWidth int) []cluster {
+	if tabWidth < 1 {
+		tabWidth = 1
+	}
+
+	runes := []rune(text)
+	clusters := make([]cluster, 0, len(runes))
+	col := 0
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		if r == '\t' {
+			width := tabWidth - (col % tabWidth)
+			clusters = append(clusters, cluster{runes: []rune{'\t'}, width: width})
+			col += width
+			i++
+			continue
+		}
+
+		c := cluster{runes: []rune{r}, width: runeWidth(r)}
+		i++
+		for i < len(runes) {
+			if runes[i] == zeroWidthJoiner && i+1 < len(runes) {
+				c.runes = append(c.runes, runes[i], runes[i+1])
+				i += 2
+			} else if isCombiningMark(runes[i]) {
+				c.runes = append(c.runes, runes[i])
+				i++
+			} else {
+				break
+			}
+		}
+
+		clusters = append(clusters, c)
+		col += c.width
+	}
+
+	return clusters
+}
+
+// columnOf returns the display column at which clusters[index] begins,
+// clamping to the line's full width if index is past the end of clusters.
+func columnOf(clusters []cluster, index int) int {
+	col := 0
+	for i := 0; i < index && i < len(clusters); i++ {
+		col += clusters[i].width
+	}
+	return col
+}
+
+// displayWidth returns the number of terminal columns text would occupy if
+// written verbatim, with no tab expansion.
+func displayWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
@@ -0,0 +1,63 @@
+package decorator
+
+import "testing"
+
+func TestSegmentClustersTabs(t *testing.T) {
+	clusters := segmentClusters("a\tb", 4)
+
+	if len(clusters) != 3 {
+		t.Fatalf("expected 3 clusters, got %d", len(clusters))
+	}
+	if clusters[0].width != 1 || clusters[0].String() != "a" {
+		t.Fatalf("unexpected first cluster: %+v", clusters[0])
+	}
+	if clusters[1].width != 3 {
+		t.Fatalf("expected tab to expand to the next 4-column stop (width 3), got %d", clusters[1].width)
+	}
+	if clusters[1].String() != "   " {
+		t.Fatalf("expected tab cluster to render as spaces, got %q", clusters[1].String())
+	}
+	if clusters[2].width != 1 || clusters[2].String() != "b" {
+		t.Fatalf("unexpected third cluster: %+v", clusters[2])
+	}
+}
+
+func TestSegmentClustersWideRunes(t *testing.T) {
+	clusters := segmentClusters("a文", 4)
+
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %d", len(clusters))
+	}
+	if clusters[1].width != 2 {
+		t.Fatalf("expected wide rune to occupy 2 columns, got %d", clusters[1].width)
+	}
+}
+
+func TestColumnOf(t *testing.T) {
+	clusters := segmentClusters("a\tbc", 4)
+
+	cases := []struct {
+		index int
+		want  int
+	}{
+		{0, 0},
+		{1, 1},
+		{2, 4},
+		{3, 5},
+		{4, 6}, // past the end clamps to the line's full width
+	}
+	for _, c := range cases {
+		if got := columnOf(clusters, c.index); got != c.want {
+			t.Errorf("columnOf(%d) = %d, want %d", c.index, got, c.want)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	if got := displayWidth("hello"); got != 5 {
+		t.Errorf("displayWidth(\"hello\") = %d, want 5", got)
+	}
+	if got := displayWidth("文"); got != 2 {
+		t.Errorf("displayWidth(\"文\") = %d, want 2", got)
+	}
+}